@@ -10,12 +10,12 @@
 //
 //		var r *http.Request
 //		name := "logger-id"
-//      logger := cloudlog.NewScopedLogger(loggingClient, r, name)
+//      logger := cloudlog.NewScopedLogger(loggingClient, "your-project-ID", r, name)
 //
 //		logger.Info("Info log entry body.")
 //		logger.Error("Error log entry body.")
 //
-//      logger.Finish()	// If you want to have the scoped logs. Otherwise all the logs will appear as individual entry
+//      logger.Finish(http.StatusOK, 0)	// If you want to have the scoped logs. Otherwise all the logs will appear as individual entry
 
 package cloudlog
 
@@ -34,3 +34,37 @@ func Configure(ctx context.Context, parent string) (*logging.Client, error) {
 	}
 	return client, nil
 }
+
+// Shutdown flushes every logger created from client and closes the
+// connection, waiting until ctx is done if that takes too long. Call it on
+// process exit so buffered entries aren't lost on Cloud Run scale-to-zero.
+func Shutdown(ctx context.Context, client *logging.Client) error {
+	done := make(chan error, 1)
+	go func() { done <- client.Close() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Writer is the leveled logging surface common to Logger, ScopedLogger, and
+// JSONLogger, so callers can depend on whichever sink fits their runtime
+// without caring which one they got.
+type Writer interface {
+	Debug(payload string)
+	Debugf(format string, v ...interface{})
+	Info(payload string)
+	Infof(format string, v ...interface{})
+	Warning(payload string)
+	Warningf(format string, v ...interface{})
+	Error(payload string)
+	Errorf(format string, v ...interface{})
+	Critical(payload string)
+	Criticalf(format string, v ...interface{})
+	Alert(payload string)
+	Alertf(format string, v ...interface{})
+	Emergency(payload string)
+	Emergencyf(format string, v ...interface{})
+}
@@ -0,0 +1,120 @@
+package cloudlog
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"cloud.google.com/go/logging"
+)
+
+// errorReportingType is the @type Cloud Error Reporting looks for in a
+// structured log entry's payload to pick it up as a reported error.
+const errorReportingType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// serviceContext returns the service/version Error Reporting groups issues
+// by, read from whichever of the Cloud Run/Cloud Functions/App Engine env
+// vars detectResource also uses is set.
+func serviceContext() map[string]interface{} {
+	service := os.Getenv("K_SERVICE")
+	if service == "" {
+		service = os.Getenv("FUNCTION_TARGET")
+	}
+	if service == "" {
+		service = os.Getenv("GAE_SERVICE")
+	}
+	version := os.Getenv("K_REVISION")
+	if version == "" {
+		version = os.Getenv("GAE_VERSION")
+	}
+	return map[string]interface{}{
+		"service": service,
+		"version": version,
+	}
+}
+
+// stack captures the current goroutine's stack trace in the same format
+// runtime produces for a panic (a "goroutine N [running]:" header followed
+// by func/file:line frames), which is the format Error Reporting's stack
+// parser requires to group issues automatically; a custom format falls back
+// to message-only grouping.
+func stack() string {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// errorReportingPayload builds the jsonPayload Cloud Error Reporting expects
+// for err: a ReportedErrorEvent with the error message, a captured stack
+// trace, and the current service/version.
+func errorReportingPayload(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"@type":          errorReportingType,
+		"message":        fmt.Sprintf("%s\n%s", err.Error(), stack()),
+		"serviceContext": serviceContext(),
+	}
+}
+
+// ReportError logs err as a Cloud Error Reporting event: Error severity, a
+// structured payload with a captured stack trace and the current
+// service/version, so it shows up grouped by issue in Error Reporting
+// instead of as an opaque string.
+func (l *Logger) ReportError(err error) {
+	e := logging.Entry{
+		Severity: logging.Error,
+		Payload:  errorReportingPayload(err),
+	}
+	l.logger.Log(e)
+}
+
+// Errorw logs err at Error severity with additional structured key/value fields. See LogAttrs.
+func (l *Logger) Errorw(err error, kv ...interface{}) {
+	l.LogAttrs(logging.Error, err.Error(), kv...)
+}
+
+// Criticalw logs err at Critical severity with additional structured key/value fields. See LogAttrs.
+func (l *Logger) Criticalw(err error, kv ...interface{}) {
+	l.LogAttrs(logging.Critical, err.Error(), kv...)
+}
+
+// ReportError logs err as a Cloud Error Reporting event, like Logger.ReportError,
+// additionally populating context.httpRequest from the request this
+// ScopedLogger was created for.
+func (l *ScopedLogger) ReportError(err error) {
+	payload := errorReportingPayload(err)
+	if l.request != nil {
+		payload["context"] = map[string]interface{}{
+			"httpRequest": map[string]interface{}{
+				"method":    l.request.Method,
+				"url":       l.request.URL.String(),
+				"userAgent": l.request.UserAgent(),
+				"remoteIp":  l.request.RemoteAddr,
+			},
+		}
+	}
+	e := logging.Entry{
+		HTTPRequest:  &logging.HTTPRequest{Request: l.request},
+		Severity:     logging.Error,
+		Payload:      payload,
+		Trace:        l.trace(),
+		SpanID:       l.spanID,
+		TraceSampled: l.traceSampled,
+	}
+	l.entryLogger.Log(e)
+	l.recordSeverity(logging.Error)
+}
+
+// Errorw logs err at Error severity with additional structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) Errorw(err error, kv ...interface{}) {
+	l.LogAttrs(logging.Error, err.Error(), kv...)
+}
+
+// Criticalw logs err at Critical severity with additional structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) Criticalw(err error, kv ...interface{}) {
+	l.LogAttrs(logging.Critical, err.Error(), kv...)
+}
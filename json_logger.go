@@ -0,0 +1,217 @@
+package cloudlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// syncWriter serializes writes to an io.Writer. It's held behind a pointer
+// and shared by a JSONLogger and every WithHTTPRequest/WithTrace copy
+// derived from it, so concurrent requests logging through the same
+// underlying writer (typically os.Stderr) still get serialized lines
+// instead of each copy locking independently.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// JSONLogger writes newline-delimited JSON entries to an io.Writer (normally
+// stderr) instead of calling the Logging API directly. On Cloud Run, GKE,
+// and Cloud Functions the Logging agent scrapes stdout/stderr and parses
+// these lines natively, filling in the monitored resource itself, so this
+// sink needs no gRPC client and has no buffering/flush pitfalls on
+// short-lived instances.
+type JSONLogger struct {
+	w       *syncWriter
+	name    string
+	request *http.Request
+	trace   string
+	spanID  string
+}
+
+// NewJSONLogger returns a JSONLogger that writes to w. name is included on
+// every line as logName so entries from different loggers are distinguishable.
+func NewJSONLogger(w io.Writer, name string) *JSONLogger {
+	return &JSONLogger{w: &syncWriter{w: w}, name: name}
+}
+
+// IsServerless reports whether the process is running in a GCP serverless
+// environment (Cloud Run, Cloud Functions, or App Engine) that auto-ingests
+// JSON written to stderr, per K_SERVICE/FUNCTION_TARGET/GAE_SERVICE.
+func IsServerless() bool {
+	return os.Getenv("K_SERVICE") != "" || os.Getenv("FUNCTION_TARGET") != "" || os.Getenv("GAE_SERVICE") != ""
+}
+
+// NewAutoLogger returns a JSONLogger writing to stderr when IsServerless
+// reports a GCP serverless environment, and a Logger backed by client
+// otherwise.
+func NewAutoLogger(client *logging.Client, name string, opts ...Option) Writer {
+	if IsServerless() {
+		return NewJSONLogger(os.Stderr, name)
+	}
+	return NewLogger(client, name, opts...)
+}
+
+// WithHTTPRequest returns a copy of l that annotates subsequent entries with r.
+func (l *JSONLogger) WithHTTPRequest(r *http.Request) *JSONLogger {
+	next := *l
+	next.request = r
+	return &next
+}
+
+// WithTrace returns a copy of l that annotates subsequent entries with the
+// given Cloud Trace trace and span IDs.
+func (l *JSONLogger) WithTrace(trace, spanID string) *JSONLogger {
+	next := *l
+	next.trace = trace
+	next.spanID = spanID
+	return &next
+}
+
+// jsonSeverity is the Cloud Logging string form of severity, e.g. "WARNING".
+func jsonSeverity(severity logging.Severity) string {
+	switch severity {
+	case logging.Debug:
+		return "DEBUG"
+	case logging.Info:
+		return "INFO"
+	case logging.Notice:
+		return "NOTICE"
+	case logging.Warning:
+		return "WARNING"
+	case logging.Error:
+		return "ERROR"
+	case logging.Critical:
+		return "CRITICAL"
+	case logging.Alert:
+		return "ALERT"
+	case logging.Emergency:
+		return "EMERGENCY"
+	default:
+		return "DEFAULT"
+	}
+}
+
+type jsonHTTPRequest struct {
+	RequestMethod string `json:"requestMethod,omitempty"`
+	RequestURL    string `json:"requestUrl,omitempty"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+}
+
+type jsonEntry struct {
+	Message  string           `json:"message"`
+	Severity string           `json:"severity"`
+	Time     string           `json:"time"`
+	LogName  string           `json:"logName,omitempty"`
+	Request  *jsonHTTPRequest `json:"httpRequest,omitempty"`
+	Trace    string           `json:"logging.googleapis.com/trace,omitempty"`
+	SpanID   string           `json:"logging.googleapis.com/spanId,omitempty"`
+}
+
+func (l *JSONLogger) output(payload string, severity logging.Severity) {
+	e := jsonEntry{
+		Message:  payload,
+		Severity: jsonSeverity(severity),
+		Time:     time.Now().Format(time.RFC3339Nano),
+		LogName:  l.name,
+		Trace:    l.trace,
+		SpanID:   l.spanID,
+	}
+	if l.request != nil {
+		e.Request = &jsonHTTPRequest{
+			RequestMethod: l.request.Method,
+			RequestURL:    l.request.URL.String(),
+			UserAgent:     l.request.UserAgent(),
+			RemoteIP:      l.request.RemoteAddr,
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.w.Write(append(line, '\n'))
+}
+
+// Debug logs the payload
+func (l *JSONLogger) Debug(payload string) {
+	l.output(payload, logging.Debug)
+}
+
+// Debugf formats according to a format specifier and logs it
+func (l *JSONLogger) Debugf(format string, v ...interface{}) {
+	l.Debug(fmt.Sprintf(format, v...))
+}
+
+// Info logs the payload
+func (l *JSONLogger) Info(payload string) {
+	l.output(payload, logging.Info)
+}
+
+// Infof formats according to a format specifier and logs it
+func (l *JSONLogger) Infof(format string, v ...interface{}) {
+	l.Info(fmt.Sprintf(format, v...))
+}
+
+// Warning logs the payload
+func (l *JSONLogger) Warning(payload string) {
+	l.output(payload, logging.Warning)
+}
+
+// Warningf formats according to a format specifier and logs it
+func (l *JSONLogger) Warningf(format string, v ...interface{}) {
+	l.Warning(fmt.Sprintf(format, v...))
+}
+
+// Error logs the payload
+func (l *JSONLogger) Error(payload string) {
+	l.output(payload, logging.Error)
+}
+
+// Errorf formats according to a format specifier and logs it
+func (l *JSONLogger) Errorf(format string, v ...interface{}) {
+	l.Error(fmt.Sprintf(format, v...))
+}
+
+// Critical logs the payload
+func (l *JSONLogger) Critical(payload string) {
+	l.output(payload, logging.Critical)
+}
+
+// Criticalf formats according to a format specifier and logs it
+func (l *JSONLogger) Criticalf(format string, v ...interface{}) {
+	l.Critical(fmt.Sprintf(format, v...))
+}
+
+// Alert logs the payload
+func (l *JSONLogger) Alert(payload string) {
+	l.output(payload, logging.Alert)
+}
+
+// Alertf formats according to a format specifier and logs it
+func (l *JSONLogger) Alertf(format string, v ...interface{}) {
+	l.Alert(fmt.Sprintf(format, v...))
+}
+
+// Emergency logs the payload
+func (l *JSONLogger) Emergency(payload string) {
+	l.output(payload, logging.Emergency)
+}
+
+// Emergencyf formats according to a format specifier and logs it
+func (l *JSONLogger) Emergencyf(format string, v ...interface{}) {
+	l.Emergency(fmt.Sprintf(format, v...))
+}
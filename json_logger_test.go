@@ -0,0 +1,31 @@
+package cloudlog
+
+import (
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestJSONSeverity(t *testing.T) {
+	tests := []struct {
+		severity logging.Severity
+		want     string
+	}{
+		{logging.Debug, "DEBUG"},
+		{logging.Info, "INFO"},
+		{logging.Notice, "NOTICE"},
+		{logging.Warning, "WARNING"},
+		{logging.Error, "ERROR"},
+		{logging.Critical, "CRITICAL"},
+		{logging.Alert, "ALERT"},
+		{logging.Emergency, "EMERGENCY"},
+		{logging.Default, "DEFAULT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := jsonSeverity(tt.severity); got != tt.want {
+				t.Errorf("jsonSeverity(%v) = %q, want %q", tt.severity, got, tt.want)
+			}
+		})
+	}
+}
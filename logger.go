@@ -1,7 +1,11 @@
 package cloudlog
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log"
 
 	"cloud.google.com/go/logging"
 )
@@ -11,9 +15,29 @@ type Logger struct {
 	logger *logging.Logger
 }
 
-// NewLogger constructs and returns a new logger object
-func NewLogger(client *logging.Client, name string) *Logger {
-	return &Logger{logger: client.Logger(name)}
+// NewLogger constructs and returns a new logger object. By default the
+// MonitoredResource is detected from the runtime environment (see
+// detectResource); pass WithResource or WithResourceDetector to override it.
+// WithOnError, WithEntrySizeThreshold, and WithDelayThreshold configure the
+// underlying client's error reporting and batching.
+func NewLogger(client *logging.Client, name string, opts ...Option) *Logger {
+	o := resolveOptions(opts)
+	if o.onError != nil {
+		client.OnError = o.onError
+	}
+	loggerOpts := append([]logging.LoggerOption{logging.CommonResource(o.resolvedResource())}, o.loggerOptions()...)
+	return &Logger{logger: client.Logger(name, loggerOpts...)}
+}
+
+// ConfigureLogger creates a logging client for projectID and returns a
+// Logger backed by it in one step. Caller is responsible to guarantee the
+// right permission to initialize the logging client.
+func ConfigureLogger(projectID string, loggerID string, opts ...Option) (*Logger, error) {
+	client, err := logging.NewClient(context.Background(), projectID)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(client, loggerID, opts...), nil
 }
 
 func (l *Logger) output(payload string, severity logging.Severity) {
@@ -31,7 +55,7 @@ func (l *Logger) Debug(payload string) {
 
 // Debugf formats according to a format specifier and logs it
 func (l *Logger) Debugf(format string, v ...interface{}) {
-	l.Debug(fmt.Sprintf(format, v))
+	l.Debug(fmt.Sprintf(format, v...))
 }
 
 // Info logs the payload
@@ -41,7 +65,7 @@ func (l *Logger) Info(payload string) {
 
 // Infof formats according to a format specifier and logs it
 func (l *Logger) Infof(format string, v ...interface{}) {
-	l.Info(fmt.Sprintf(format, v))
+	l.Info(fmt.Sprintf(format, v...))
 }
 
 // Warning logs the payload
@@ -51,7 +75,7 @@ func (l *Logger) Warning(payload string) {
 
 // Warningf formats according to a format specifier and logs it
 func (l *Logger) Warningf(format string, v ...interface{}) {
-	l.Warning(fmt.Sprintf(format, v))
+	l.Warning(fmt.Sprintf(format, v...))
 }
 
 // Error logs the payload
@@ -61,7 +85,7 @@ func (l *Logger) Error(payload string) {
 
 // Errorf formats according to a format specifier and logs it
 func (l *Logger) Errorf(format string, v ...interface{}) {
-	l.Error(fmt.Sprintf(format, v))
+	l.Error(fmt.Sprintf(format, v...))
 }
 
 // Critical logs the payload
@@ -71,7 +95,7 @@ func (l *Logger) Critical(payload string) {
 
 // Criticalf formats according to a format specifier and logs it
 func (l *Logger) Criticalf(format string, v ...interface{}) {
-	l.Critical(fmt.Sprintf(format, v))
+	l.Critical(fmt.Sprintf(format, v...))
 }
 
 // Alert logs the payload
@@ -81,7 +105,7 @@ func (l *Logger) Alert(payload string) {
 
 // Alertf formats according to a format specifier and logs it
 func (l *Logger) Alertf(format string, v ...interface{}) {
-	l.Alert(fmt.Sprintf(format, v))
+	l.Alert(fmt.Sprintf(format, v...))
 }
 
 // Emergency logs the payload
@@ -91,5 +115,50 @@ func (l *Logger) Emergency(payload string) {
 
 // Emergencyf formats according to a format specifier and logs it
 func (l *Logger) Emergencyf(format string, v ...interface{}) {
-	l.Emergency(fmt.Sprintf(format, v))
+	l.Emergency(fmt.Sprintf(format, v...))
+}
+
+// severityWriter is an io.Writer that logs each complete line written to it
+// as its own entry at a fixed severity, buffering partial writes until a
+// newline arrives, the same pattern used to redirect stderr to Cloud Logging
+// on GCE. log is a field rather than a direct call to a *Logger so tests can
+// substitute a stub.
+type severityWriter struct {
+	log      func(payload string, severity logging.Severity)
+	severity logging.Severity
+	buf      bytes.Buffer
+}
+
+func (w *severityWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// No newline yet; put the partial line back and wait for more.
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.log(line[:len(line)-1], w.severity)
+	}
+	return len(p), nil
+}
+
+// Writer returns an io.Writer that logs every line written to it as its own
+// entry at severity, so standard-library loggers and other io.Writer-based
+// APIs can be pointed at Cloud Logging.
+func (l *Logger) Writer(severity logging.Severity) io.Writer {
+	return &severityWriter{log: l.output, severity: severity}
+}
+
+// StdLogger returns a *log.Logger that writes to Writer(severity), for
+// pointing things like http.Server.ErrorLog at Cloud Logging.
+func (l *Logger) StdLogger(severity logging.Severity) *log.Logger {
+	return log.New(l.Writer(severity), "", 0)
+}
+
+// Close flushes this logger's buffered entries. It does not close the
+// underlying client; call Shutdown for that.
+func (l *Logger) Close() error {
+	return l.logger.Flush()
 }
@@ -0,0 +1,67 @@
+package cloudlog
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestSeverityWriterWrite(t *testing.T) {
+	type logged struct {
+		payload  string
+		severity logging.Severity
+	}
+
+	tests := []struct {
+		name    string
+		writes  []string
+		want    []logged
+		wantBuf string
+	}{
+		{
+			name:   "single line",
+			writes: []string{"hello\n"},
+			want:   []logged{{"hello", logging.Warning}},
+		},
+		{
+			name:   "multiple lines in one write",
+			writes: []string{"first\nsecond\n"},
+			want:   []logged{{"first", logging.Warning}, {"second", logging.Warning}},
+		},
+		{
+			name:    "partial line is buffered until a newline arrives",
+			writes:  []string{"partial"},
+			want:    nil,
+			wantBuf: "partial",
+		},
+		{
+			name:   "partial line completed by a later write",
+			writes: []string{"par", "tial\n"},
+			want:   []logged{{"partial", logging.Warning}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []logged
+			w := &severityWriter{
+				log: func(payload string, severity logging.Severity) {
+					got = append(got, logged{payload, severity})
+				},
+				severity: logging.Warning,
+			}
+			for _, s := range tt.writes {
+				if _, err := w.Write([]byte(s)); err != nil {
+					t.Fatalf("Write(%q) returned error: %v", s, err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logged entries = %+v, want %+v", got, tt.want)
+			}
+			if buf := w.buf.String(); buf != tt.wantBuf {
+				t.Errorf("buffered partial line = %q, want %q", buf, tt.wantBuf)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package cloudlog
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"cloud.google.com/go/logging"
+	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// Option configures a Logger or ScopedLogger at construction time: which
+// MonitoredResource its entries report, how its underlying *logging.Logger
+// batches and reports errors, and (ScopedLogger only) whether it emits
+// partial-finish entries for long-running requests.
+type Option func(*options)
+
+type options struct {
+	resource              *mrpb.MonitoredResource
+	resourceDetector      func() *mrpb.MonitoredResource
+	onError               func(error)
+	entrySizeThreshold    int
+	delayThreshold        time.Duration
+	partialFinishInterval time.Duration
+}
+
+// WithResource attaches resource to every entry, bypassing detectResource entirely.
+func WithResource(resource *mrpb.MonitoredResource) Option {
+	return func(o *options) { o.resource = resource }
+}
+
+// WithResourceDetector replaces the built-in GKE/Cloud Run/Cloud
+// Functions/App Engine/global detection with detector.
+func WithResourceDetector(detector func() *mrpb.MonitoredResource) Option {
+	return func(o *options) { o.resourceDetector = detector }
+}
+
+// WithOnError sets the function called when the underlying client fails to
+// send log entries, as with (*logging.Client).OnError.
+func WithOnError(onError func(error)) Option {
+	return func(o *options) { o.onError = onError }
+}
+
+// WithEntrySizeThreshold overrides the underlying logger's buffered byte
+// count before a flush is triggered, as with logging.EntryByteThreshold.
+func WithEntrySizeThreshold(bytes int) Option {
+	return func(o *options) { o.entrySizeThreshold = bytes }
+}
+
+// WithDelayThreshold overrides the underlying logger's maximum delay before
+// buffered entries are flushed, as with logging.DelayThreshold.
+func WithDelayThreshold(d time.Duration) Option {
+	return func(o *options) { o.delayThreshold = d }
+}
+
+// WithPartialFinishInterval makes NewScopedLogger start a goroutine, stopped
+// by Finish or Close, that logs a partial aggregation entry to the parent
+// log every d. That gives long-running requests (streaming, websockets,
+// background jobs) a parent entry in the Logs UI before they complete.
+func WithPartialFinishInterval(d time.Duration) Option {
+	return func(o *options) { o.partialFinishInterval = d }
+}
+
+// resolveOptions applies opts and returns the resulting options.
+func resolveOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// resolvedResource returns the MonitoredResource a Logger or ScopedLogger
+// built with o should attach to its entries.
+func (o options) resolvedResource() *mrpb.MonitoredResource {
+	switch {
+	case o.resource != nil:
+		return o.resource
+	case o.resourceDetector != nil:
+		return o.resourceDetector()
+	default:
+		return detectResource()
+	}
+}
+
+// loggerOptions returns the logging.LoggerOptions corresponding to o's
+// batching knobs, in addition to CommonResource/CommonLabels which callers
+// set up themselves.
+func (o options) loggerOptions() []logging.LoggerOption {
+	var lopts []logging.LoggerOption
+	if o.entrySizeThreshold > 0 {
+		lopts = append(lopts, logging.EntryByteThreshold(o.entrySizeThreshold))
+	}
+	if o.delayThreshold > 0 {
+		lopts = append(lopts, logging.DelayThreshold(o.delayThreshold))
+	}
+	return lopts
+}
+
+// detectResource inspects the runtime environment and returns the
+// MonitoredResource Cloud Logging expects for it: k8s_container on GKE,
+// cloud_run_revision on Cloud Run, cloud_function on Cloud Functions,
+// gae_app on App Engine, or the global fallback everywhere else.
+func detectResource() *mrpb.MonitoredResource {
+	switch {
+	case os.Getenv("FUNCTION_TARGET") != "":
+		return cloudFunctionResource()
+	case os.Getenv("K_SERVICE") != "":
+		return cloudRunResource()
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		return k8sContainerResource()
+	case os.Getenv("GAE_SERVICE") != "":
+		return gaeAppResource()
+	default:
+		return &mrpb.MonitoredResource{Type: "global"}
+	}
+}
+
+// gcpProjectID returns the current project ID, or "" if unavailable (e.g.
+// not running on GCP).
+func gcpProjectID() string {
+	if !metadata.OnGCE() {
+		return ""
+	}
+	id, err := metadata.ProjectID()
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// gcpRegion returns the region of the current GCE zone, or "" if
+// unavailable. Zones look like "us-central1-a"; the region drops the
+// trailing "-a".
+func gcpRegion() string {
+	if !metadata.OnGCE() {
+		return ""
+	}
+	zone, err := metadata.Zone()
+	if err != nil {
+		return ""
+	}
+	if i := strings.LastIndex(zone, "-"); i >= 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+func cloudRunResource() *mrpb.MonitoredResource {
+	return &mrpb.MonitoredResource{
+		Type: "cloud_run_revision",
+		Labels: map[string]string{
+			"project_id":         gcpProjectID(),
+			"service_name":       os.Getenv("K_SERVICE"),
+			"revision_name":      os.Getenv("K_REVISION"),
+			"configuration_name": os.Getenv("K_CONFIGURATION"),
+			"location":           gcpRegion(),
+		},
+	}
+}
+
+func cloudFunctionResource() *mrpb.MonitoredResource {
+	return &mrpb.MonitoredResource{
+		Type: "cloud_function",
+		Labels: map[string]string{
+			"project_id":    gcpProjectID(),
+			"function_name": os.Getenv("FUNCTION_TARGET"),
+			"region":        gcpRegion(),
+		},
+	}
+}
+
+func k8sContainerResource() *mrpb.MonitoredResource {
+	return &mrpb.MonitoredResource{
+		Type: "k8s_container",
+		Labels: map[string]string{
+			"project_id":     gcpProjectID(),
+			"location":       gcpRegion(),
+			"cluster_name":   os.Getenv("CLUSTER_NAME"),
+			"namespace_name": os.Getenv("NAMESPACE"),
+			"pod_name":       os.Getenv("POD_NAME"),
+		},
+	}
+}
+
+func gaeAppResource() *mrpb.MonitoredResource {
+	return &mrpb.MonitoredResource{
+		Type: "gae_app",
+		Labels: map[string]string{
+			"project_id": gcpProjectID(),
+			"module_id":  os.Getenv("GAE_SERVICE"),
+			"version_id": os.Getenv("GAE_VERSION"),
+		},
+	}
+}
@@ -1,16 +1,18 @@
 package cloudlog
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/compute/metadata"
 	"cloud.google.com/go/logging"
-	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
 // ScopedLogger log information to Stackdrive console to be grouped based on the request
@@ -18,37 +20,53 @@ type ScopedLogger struct {
 	entryLogger   *logging.Logger
 	parentLogger  *logging.Logger
 	request       *http.Request
+	severityMu    sync.Mutex
 	logSeverities []logging.Severity
 	startTime     time.Time
 	endTime       time.Time
 	local         bool
+	projectID     string
+	traceID       string
+	spanID        string
+	traceSampled  bool
+	commonLabels  map[string]string
+
+	partialFinishStop chan struct{}
+	partialFinishOnce sync.Once
 }
 
-// NewScopedLogger constructs and returns a new ScopedLogger.
-func NewScopedLogger(client *logging.Client, r *http.Request, name string) *ScopedLogger {
+// NewScopedLogger constructs and returns a new ScopedLogger. projectID is
+// used to build the `projects/<projectID>/traces/<traceID>` value Cloud
+// Trace expects in Entry.Trace. By default the MonitoredResource is detected
+// from the runtime environment (see detectResource); pass WithResource or
+// WithResourceDetector to override it. WithOnError, WithEntrySizeThreshold,
+// and WithDelayThreshold configure the underlying client's error reporting
+// and batching, and WithPartialFinishInterval enables periodic partial-finish
+// entries for long-running requests.
+func NewScopedLogger(client *logging.Client, projectID string, r *http.Request, name string, opts ...Option) *ScopedLogger {
 	const (
 		// parentFormat is a format string for a ScopedLogger's parent log name.
 		parentFormat = "%v-request"
 		// childFormat is a format string for a ScopedLogger's child log name.
 		childFormat = "%v-entry"
 	)
-	// To aggregate all logs under the same resource tab
-	customResource := &mrpb.MonitoredResource{
-		Type: "gce_instance",
+	o := resolveOptions(opts)
+	if o.onError != nil {
+		client.OnError = o.onError
 	}
-	parentLogger := client.Logger(
-		fmt.Sprintf(parentFormat, name),
-		logging.CommonResource(customResource),
-		logging.CommonLabels(WithHostname(nil)),
-	)
-	childLogger := client.Logger(
-		fmt.Sprintf(childFormat, name),
+	// To aggregate all logs under the same resource tab
+	customResource := o.resolvedResource()
+	commonLabels := WithHostname(nil)
+	loggerOpts := append([]logging.LoggerOption{
 		logging.CommonResource(customResource),
-		logging.CommonLabels(WithHostname(nil)),
-	)
+		logging.CommonLabels(commonLabels),
+	}, o.loggerOptions()...)
+	parentLogger := client.Logger(fmt.Sprintf(parentFormat, name), loggerOpts...)
+	childLogger := client.Logger(fmt.Sprintf(childFormat, name), loggerOpts...)
 	startTime := time.Now()
 	endTime := startTime
-	return &ScopedLogger{
+	traceID, spanID, sampled := parseTraceContext(r)
+	l := &ScopedLogger{
 		entryLogger:   childLogger,
 		parentLogger:  parentLogger,
 		request:       r,
@@ -56,14 +74,96 @@ func NewScopedLogger(client *logging.Client, r *http.Request, name string) *Scop
 		startTime:     startTime,
 		endTime:       endTime,
 		local:         false,
+		projectID:     projectID,
+		traceID:       traceID,
+		spanID:        spanID,
+		traceSampled:  sampled,
+		commonLabels:  commonLabels,
+	}
+	if o.partialFinishInterval > 0 {
+		l.partialFinishStop = make(chan struct{})
+		go l.runPartialFinish(o.partialFinishInterval)
+	}
+	return l
+}
+
+// runPartialFinish logs a partial-finish entry every d until stopPartialFinish is called.
+func (l *ScopedLogger) runPartialFinish(d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.partialFinish()
+		case <-l.partialFinishStop:
+			return
+		}
 	}
 }
 
+// stopPartialFinish stops the goroutine started by WithPartialFinishInterval,
+// if any. It's safe to call more than once.
+func (l *ScopedLogger) stopPartialFinish() {
+	l.partialFinishOnce.Do(func() {
+		if l.partialFinishStop != nil {
+			close(l.partialFinishStop)
+		}
+	})
+}
+
+// parseTraceContext extracts the trace ID, span ID, and sampling decision
+// from a request's X-Cloud-Trace-Context header, formatted
+// "TRACE_ID/SPAN_ID;o=1" per https://cloud.google.com/trace/docs/setup. The
+// returned span ID is the 16-character hex string logging.Entry.SpanID
+// expects, converted from the header's decimal form.
+func parseTraceContext(r *http.Request) (traceID, spanID string, sampled bool) {
+	if r == nil {
+		return "", "", false
+	}
+	header := r.Header.Get("X-Cloud-Trace-Context")
+	if header == "" {
+		return "", "", false
+	}
+	traceID, rest := header, ""
+	if i := strings.IndexByte(header, '/'); i >= 0 {
+		traceID, rest = header[:i], header[i+1:]
+	}
+	decimalSpanID := rest
+	if i := strings.IndexByte(rest, ';'); i >= 0 {
+		decimalSpanID = rest[:i]
+		sampled = strings.Contains(rest[i:], "o=1")
+	}
+	return traceID, spanIDToHex(decimalSpanID), sampled
+}
+
+// spanIDToHex converts a decimal uint64 span ID, as carried in
+// X-Cloud-Trace-Context, to the zero-padded 16-character hex string Cloud
+// Trace and logging.Entry.SpanID expect. It returns "" if s isn't a valid
+// decimal uint64.
+func spanIDToHex(s string) string {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%016x", id)
+}
+
+// trace formats the ScopedLogger's trace ID as the resource name Cloud
+// Trace expects. It returns "" if the request carried no trace context.
+func (l *ScopedLogger) trace() string {
+	if l.traceID == "" {
+		return ""
+	}
+	return fmt.Sprintf("projects/%s/traces/%s", l.projectID, l.traceID)
+}
+
 func (l *ScopedLogger) EnableLocal(flag bool) {
 	l.local = flag
 }
 
 func (l *ScopedLogger) maxSeverity() logging.Severity {
+	l.severityMu.Lock()
+	defer l.severityMu.Unlock()
 	maxSeverity := logging.Default
 	for _, s := range l.logSeverities {
 		if s > maxSeverity {
@@ -73,21 +173,149 @@ func (l *ScopedLogger) maxSeverity() logging.Severity {
 	return maxSeverity
 }
 
+// recordSeverity appends severity to the set maxSeverity scans. It's guarded
+// by severityMu because the partial-finish goroutine can call maxSeverity
+// concurrently with request-handling goroutines logging entries.
+func (l *ScopedLogger) recordSeverity(severity logging.Severity) {
+	l.severityMu.Lock()
+	l.logSeverities = append(l.logSeverities, severity)
+	l.severityMu.Unlock()
+}
+
 func (l *ScopedLogger) output(payload string, severity logging.Severity) {
 	e := logging.Entry{
 		HTTPRequest: &logging.HTTPRequest{
 			Request: l.request,
 		},
-		Payload:  payload,
-		Severity: severity,
+		Payload:      payload,
+		Severity:     severity,
+		Trace:        l.trace(),
+		SpanID:       l.spanID,
+		TraceSampled: l.traceSampled,
 	}
 	l.entryLogger.Log(e)
-	l.logSeverities = append(l.logSeverities, severity)
+	l.recordSeverity(severity)
 	if l.local {
 		log.Printf("%v: %v", severity.String(), payload)
 	}
 }
 
+// outputCtx is output plus the trace ID override and labels carried on ctx,
+// via WithTraceID and WithLabels.
+func (l *ScopedLogger) outputCtx(ctx context.Context, payload string, severity logging.Severity) {
+	e := logging.Entry{
+		HTTPRequest: &logging.HTTPRequest{
+			Request: l.request,
+		},
+		Payload:      payload,
+		Severity:     severity,
+		Trace:        l.trace(),
+		SpanID:       l.spanID,
+		TraceSampled: l.traceSampled,
+	}
+	if traceID, ok := traceIDFromContext(ctx); ok {
+		e.Trace = fmt.Sprintf("projects/%s/traces/%s", l.projectID, traceID)
+	}
+	if labels := labelsFromContext(ctx); len(labels) > 0 {
+		merged := make(map[string]string, len(labels)+len(l.commonLabels))
+		for k, v := range l.commonLabels {
+			merged[k] = v
+		}
+		for k, v := range labels {
+			merged[k] = v
+		}
+		e.Labels = merged
+	}
+	l.entryLogger.Log(e)
+	l.recordSeverity(severity)
+	if l.local {
+		log.Printf("%v: %v", severity.String(), payload)
+	}
+}
+
+// DebugCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) DebugCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Debug)
+}
+
+// InfoCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) InfoCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Info)
+}
+
+// WarningCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) WarningCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Warning)
+}
+
+// ErrorCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) ErrorCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Error)
+}
+
+// CriticalCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) CriticalCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Critical)
+}
+
+// AlertCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) AlertCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Alert)
+}
+
+// EmergencyCtx logs the payload, tagging the entry with the trace ID and labels carried on ctx.
+func (l *ScopedLogger) EmergencyCtx(ctx context.Context, payload string) {
+	l.outputCtx(ctx, payload, logging.Emergency)
+}
+
+type scopedLoggerContextKey struct{}
+type traceIDContextKey struct{}
+type labelsContextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, retrievable with FromContext.
+func NewContext(ctx context.Context, l *ScopedLogger) context.Context {
+	return context.WithValue(ctx, scopedLoggerContextKey{}, l)
+}
+
+// FromContext returns the ScopedLogger stored in ctx by NewContext or
+// Middleware, and whether one was found.
+func FromContext(ctx context.Context) (*ScopedLogger, bool) {
+	l, ok := ctx.Value(scopedLoggerContextKey{}).(*ScopedLogger)
+	return l, ok
+}
+
+// WithTraceID returns a copy of ctx whose entries, when logged through a
+// *Ctx method, report id instead of the ScopedLogger's own request-derived
+// trace ID. Useful for background work kicked off from a request that should
+// still be linked to it in Cloud Trace.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey{}, id)
+}
+
+func traceIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDContextKey{}).(string)
+	return id, ok
+}
+
+// WithLabels returns a copy of ctx with kv (alternating key, value) merged
+// into the labels that a *Ctx method will attach to its entry, on top of any
+// labels already present on ctx.
+func WithLabels(ctx context.Context, kv ...string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range labelsFromContext(ctx) {
+		merged[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		merged[kv[i]] = kv[i+1]
+	}
+	return context.WithValue(ctx, labelsContextKey{}, merged)
+}
+
+func labelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsContextKey{}).(map[string]string)
+	return labels
+}
+
 // Debug logs the payload
 func (l *ScopedLogger) Debug(payload string) {
 	l.output(payload, logging.Debug)
@@ -159,13 +387,15 @@ func (l *ScopedLogger) Emergencyf(format string, v ...interface{}) {
 }
 
 // Finish doesn't log any payload, it just provides the http request, response size and status code
-func (l *ScopedLogger) Finish() {
+func (l *ScopedLogger) Finish(status int, size int64) {
+	l.stopPartialFinish()
 	l.endTime = time.Now()
 	e := logging.Entry{
 		HTTPRequest: &logging.HTTPRequest{
-			Request: l.request,
-			Latency: l.endTime.Sub(l.startTime),
-			//Status:  200,
+			Request:      l.request,
+			Latency:      l.endTime.Sub(l.startTime),
+			Status:       status,
+			ResponseSize: size,
 		},
 		Severity: l.maxSeverity(),
 	}
@@ -187,6 +417,53 @@ func (l *ScopedLogger) partialFinish() {
 	l.parentLogger.Log(e)
 }
 
+// Close stops any partial-finish goroutine and flushes buffered entries for
+// both the parent and child loggers. It does not close the underlying
+// client; call Shutdown for that.
+func (l *ScopedLogger) Close() error {
+	l.stopPartialFinish()
+	entryErr := l.entryLogger.Flush()
+	if err := l.parentLogger.Flush(); entryErr == nil {
+		return err
+	}
+	return entryErr
+}
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code and
+// response size written through it, for Middleware's call to Finish.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// Middleware returns an http middleware that creates a ScopedLogger for each
+// request, injects it into the request context (retrievable with
+// FromContext), and calls Finish with the real response status and size once
+// the handler returns.
+func Middleware(client *logging.Client, projectID, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := NewScopedLogger(client, projectID, r, name)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			ctx := NewContext(r.Context(), logger)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			logger.Finish(rec.status, rec.size)
+		})
+	}
+}
+
 var detectedHost struct {
 	hostname string
 	once     sync.Once
@@ -0,0 +1,94 @@
+package cloudlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantTrace  string
+		wantSpan   string
+		wantSample bool
+	}{
+		{
+			name:       "no header",
+			header:     "",
+			wantTrace:  "",
+			wantSpan:   "",
+			wantSample: false,
+		},
+		{
+			name:       "trace and span, sampled",
+			header:     "105445aa7843bc8bf206b12000100000/1;o=1",
+			wantTrace:  "105445aa7843bc8bf206b12000100000",
+			wantSpan:   "0000000000000001",
+			wantSample: true,
+		},
+		{
+			name:       "trace and span, not sampled",
+			header:     "105445aa7843bc8bf206b12000100000/1;o=0",
+			wantTrace:  "105445aa7843bc8bf206b12000100000",
+			wantSpan:   "0000000000000001",
+			wantSample: false,
+		},
+		{
+			name:       "trace only, no span",
+			header:     "105445aa7843bc8bf206b12000100000",
+			wantTrace:  "105445aa7843bc8bf206b12000100000",
+			wantSpan:   "",
+			wantSample: false,
+		},
+		{
+			name:       "large span ID",
+			header:     "105445aa7843bc8bf206b12000100000/18446744073709551615;o=1",
+			wantTrace:  "105445aa7843bc8bf206b12000100000",
+			wantSpan:   "ffffffffffffffff",
+			wantSample: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Cloud-Trace-Context", tt.header)
+			}
+			traceID, spanID, sampled := parseTraceContext(r)
+			if traceID != tt.wantTrace || spanID != tt.wantSpan || sampled != tt.wantSample {
+				t.Errorf("parseTraceContext(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.header, traceID, spanID, sampled, tt.wantTrace, tt.wantSpan, tt.wantSample)
+			}
+		})
+	}
+
+	t.Run("nil request", func(t *testing.T) {
+		traceID, spanID, sampled := parseTraceContext(nil)
+		if traceID != "" || spanID != "" || sampled {
+			t.Errorf("parseTraceContext(nil) = (%q, %q, %v), want (\"\", \"\", false)", traceID, spanID, sampled)
+		}
+	})
+}
+
+func TestSpanIDToHex(t *testing.T) {
+	tests := []struct {
+		decimal string
+		want    string
+	}{
+		{"1", "0000000000000001"},
+		{"0", "0000000000000000"},
+		{"18446744073709551615", "ffffffffffffffff"},
+		{"not-a-number", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.decimal, func(t *testing.T) {
+			if got := spanIDToHex(tt.decimal); got != tt.want {
+				t.Errorf("spanIDToHex(%q) = %q, want %q", tt.decimal, got, tt.want)
+			}
+		})
+	}
+}
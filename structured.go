@@ -0,0 +1,276 @@
+package cloudlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"cloud.google.com/go/logging"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+)
+
+// Special Cloud Logging JSON payload keys. FieldLabels, FieldTrace,
+// FieldSpanID, FieldInsertID, FieldHTTPRequest, and FieldSourceLocation are
+// recognized by LogAttrs and promoted onto the matching logging.Entry field
+// instead of being left in Payload. FieldStackTrace has no corresponding
+// Entry field (it's only meaningful to Error Reporting's jsonPayload
+// parsing, see ReportError), so LogAttrs leaves it nested in Payload.
+const (
+	FieldMessage        = "message"
+	FieldSeverity       = "severity"
+	FieldHTTPRequest    = "httpRequest"
+	FieldLabels         = "logging.googleapis.com/labels"
+	FieldSourceLocation = "logging.googleapis.com/sourceLocation"
+	FieldTrace          = "logging.googleapis.com/trace"
+	FieldSpanID         = "logging.googleapis.com/spanId"
+	FieldInsertID       = "logging.googleapis.com/insertId"
+	FieldStackTrace     = "logging.googleapis.com/stack_trace"
+)
+
+// kvToMap turns an alternating key/value list (as accepted by slog and the
+// *KV helpers below) into a payload map. A key that isn't a string is
+// stringified with its position so a malformed call still logs something
+// useful instead of panicking.
+func kvToMap(kv ...interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprintf("!BADKEY-%d", i)
+		}
+		if i+1 < len(kv) {
+			m[key] = kv[i+1]
+		} else {
+			m[key] = nil
+		}
+	}
+	return m
+}
+
+// applyFields pulls the special Cloud Logging keys out of fields and sets
+// them on e directly, leaving the rest as the structured Payload.
+func applyFields(e *logging.Entry, msg string, fields map[string]interface{}) {
+	payload := make(map[string]interface{}, len(fields)+1)
+	if msg != "" {
+		payload[FieldMessage] = msg
+	}
+	for k, v := range fields {
+		switch k {
+		case FieldLabels:
+			if labels, ok := v.(map[string]string); ok {
+				e.Labels = labels
+			}
+		case FieldTrace:
+			if trace, ok := v.(string); ok {
+				e.Trace = trace
+			}
+		case FieldSpanID:
+			if spanID, ok := v.(string); ok {
+				e.SpanID = spanID
+			}
+		case FieldInsertID:
+			if insertID, ok := v.(string); ok {
+				e.InsertID = insertID
+			}
+		case FieldHTTPRequest:
+			if hr, ok := v.(*logging.HTTPRequest); ok {
+				e.HTTPRequest = hr
+			}
+		case FieldSourceLocation:
+			if sl, ok := v.(*logpb.LogEntrySourceLocation); ok {
+				e.SourceLocation = sl
+			}
+		default:
+			payload[k] = v
+		}
+	}
+	e.Payload = payload
+}
+
+// LogAttrs logs msg at severity with a structured Payload built from fields,
+// an alternating key/value list. Recognized Cloud Logging keys (FieldLabels,
+// FieldTrace, FieldSpanID, FieldInsertID, FieldHTTPRequest, FieldSourceLocation)
+// are promoted onto the corresponding logging.Entry field instead of being
+// nested in Payload.
+func (l *Logger) LogAttrs(severity logging.Severity, msg string, fields ...interface{}) {
+	e := logging.Entry{Severity: severity}
+	applyFields(&e, msg, kvToMap(fields...))
+	l.logger.Log(e)
+}
+
+// InfoKV logs msg at Info severity with structured key/value fields. See LogAttrs.
+func (l *Logger) InfoKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Info, msg, kv...)
+}
+
+// DebugKV logs msg at Debug severity with structured key/value fields. See LogAttrs.
+func (l *Logger) DebugKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Debug, msg, kv...)
+}
+
+// WarningKV logs msg at Warning severity with structured key/value fields. See LogAttrs.
+func (l *Logger) WarningKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Warning, msg, kv...)
+}
+
+// ErrorKV logs msg at Error severity with structured key/value fields. See LogAttrs.
+func (l *Logger) ErrorKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Error, msg, kv...)
+}
+
+// CriticalKV logs msg at Critical severity with structured key/value fields. See LogAttrs.
+func (l *Logger) CriticalKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Critical, msg, kv...)
+}
+
+// AlertKV logs msg at Alert severity with structured key/value fields. See LogAttrs.
+func (l *Logger) AlertKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Alert, msg, kv...)
+}
+
+// EmergencyKV logs msg at Emergency severity with structured key/value fields. See LogAttrs.
+func (l *Logger) EmergencyKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Emergency, msg, kv...)
+}
+
+// LogAttrs logs msg at severity with a structured Payload built from fields,
+// merging in the request's HTTPRequest the same way output does. See
+// Logger.LogAttrs for how the special Cloud Logging keys are handled.
+func (l *ScopedLogger) LogAttrs(severity logging.Severity, msg string, fields ...interface{}) {
+	e := logging.Entry{
+		HTTPRequest:  &logging.HTTPRequest{Request: l.request},
+		Severity:     severity,
+		Trace:        l.trace(),
+		SpanID:       l.spanID,
+		TraceSampled: l.traceSampled,
+	}
+	applyFields(&e, msg, kvToMap(fields...))
+	l.entryLogger.Log(e)
+	l.recordSeverity(severity)
+}
+
+// InfoKV logs msg at Info severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) InfoKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Info, msg, kv...)
+}
+
+// DebugKV logs msg at Debug severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) DebugKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Debug, msg, kv...)
+}
+
+// WarningKV logs msg at Warning severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) WarningKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Warning, msg, kv...)
+}
+
+// ErrorKV logs msg at Error severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) ErrorKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Error, msg, kv...)
+}
+
+// CriticalKV logs msg at Critical severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) CriticalKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Critical, msg, kv...)
+}
+
+// AlertKV logs msg at Alert severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) AlertKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Alert, msg, kv...)
+}
+
+// EmergencyKV logs msg at Emergency severity with structured key/value fields. See LogAttrs.
+func (l *ScopedLogger) EmergencyKV(msg string, kv ...interface{}) {
+	l.LogAttrs(logging.Emergency, msg, kv...)
+}
+
+// slogLevelToSeverity maps a log/slog level onto the closest Cloud Logging
+// severity. slog only has four standard levels, mapped onto Debug, Info,
+// Warning, and Error; any level at or above slog.LevelError maps to Error, so
+// Notice, Critical, Alert, and Emergency are never produced.
+func slogLevelToSeverity(level slog.Level) logging.Severity {
+	switch {
+	case level >= slog.LevelError:
+		return logging.Error
+	case level >= slog.LevelWarn:
+		return logging.Warning
+	case level >= slog.LevelInfo:
+		return logging.Info
+	default:
+		return logging.Debug
+	}
+}
+
+// SlogHandler adapts a Logger to the slog.Handler interface, so callers can
+// use log/slog end-to-end while still emitting entries Cloud Logging parses
+// as structured payloads.
+type SlogHandler struct {
+	logger *Logger
+	attrs  []slog.Attr
+	group  string
+}
+
+// NewSlogHandler returns a slog.Handler that forwards records to logger.
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+// Cloud Logging accepts every severity, so every level is enabled.
+func (h *SlogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle logs record through the underlying Logger. h.attrs already carry
+// whatever group prefix was active when they were added via WithAttrs, so
+// only the record's own attrs are prefixed with the group active now.
+func (h *SlogHandler) Handle(_ context.Context, record slog.Record) error {
+	fields := make(map[string]interface{}, len(h.attrs)+record.NumAttrs())
+	for _, a := range h.attrs {
+		fields[a.Key] = a.Value.Any()
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		key := a.Key
+		if h.group != "" {
+			key = h.group + "." + key
+		}
+		fields[key] = a.Value.Any()
+		return true
+	})
+
+	e := logging.Entry{
+		Timestamp: record.Time,
+		Severity:  slogLevelToSeverity(record.Level),
+	}
+	applyFields(&e, record.Message, fields)
+	h.logger.logger.Log(e)
+	return nil
+}
+
+// WithAttrs returns a new handler with attrs added to every subsequent
+// record. Keys are prefixed with whatever group is active now, so attrs
+// added before a later WithGroup keep their own (possibly unprefixed) keys
+// instead of being relabeled when that later group is applied.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	prefixed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		prefixed[i] = a
+	}
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), prefixed...)
+	return &next
+}
+
+// WithGroup returns a new handler that nests subsequent attributes under name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	if h.group != "" {
+		next.group = h.group + "." + name
+	} else {
+		next.group = name
+	}
+	return &next
+}
@@ -0,0 +1,68 @@
+package cloudlog
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestKVToMap(t *testing.T) {
+	tests := []struct {
+		name string
+		kv   []interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "empty",
+			kv:   nil,
+			want: map[string]interface{}{},
+		},
+		{
+			name: "string keys",
+			kv:   []interface{}{"count", 3, "user", "alice"},
+			want: map[string]interface{}{"count": 3, "user": "alice"},
+		},
+		{
+			name: "dangling key with no value",
+			kv:   []interface{}{"orphan"},
+			want: map[string]interface{}{"orphan": nil},
+		},
+		{
+			name: "non-string key is stringified by position",
+			kv:   []interface{}{42, "value"},
+			want: map[string]interface{}{"!BADKEY-0": "value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := kvToMap(tt.kv...)
+			if len(got) != len(tt.want) {
+				t.Fatalf("kvToMap(%v) = %v, want %v", tt.kv, got, tt.want)
+			}
+			for k, wantV := range tt.want {
+				gotV, ok := got[k]
+				if !ok || gotV != wantV {
+					t.Errorf("kvToMap(%v)[%q] = %v, want %v", tt.kv, k, gotV, wantV)
+				}
+			}
+		})
+	}
+}
+
+func TestSlogHandlerWithAttrsKeepsKeysFromBeforeGroup(t *testing.T) {
+	h := &SlogHandler{}
+	withA := h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).(*SlogHandler)
+	withGroup := withA.WithGroup("g").(*SlogHandler)
+	withB := withGroup.WithAttrs([]slog.Attr{slog.Int("b", 2)}).(*SlogHandler)
+
+	got := make(map[string]int64)
+	for _, a := range withB.attrs {
+		got[a.Key] = a.Value.Int64()
+	}
+
+	if got["a"] != 1 {
+		t.Errorf("attr added before WithGroup got relabeled: keys = %v, want \"a\" unprefixed", got)
+	}
+	if got["g.b"] != 2 {
+		t.Errorf("attr added after WithGroup was not prefixed: keys = %v, want \"g.b\"", got)
+	}
+}